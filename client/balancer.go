@@ -0,0 +1,365 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// [Best #21] 클라이언트 사이드 로드밸런싱 고도화: serverList[rand.Intn(...)] 한 줄짜리
+// 선택 로직을 pluggable Balancer로 대체하고, 엔드포인트별 실패 횟수를 추적하는 서킷
+// 브레이커와 백그라운드 DNS 리프레시를 더해 운영 중 재시작 없이 백엔드 풀을 늘릴 수 있게 한다.
+
+// Balancer는 라우팅 토큰(있다면)을 받아 다이얼할 엔드포인트를 고른다.
+// ok가 false면 쓸 수 있는 엔드포인트가 없다는 뜻이다.
+type Balancer interface {
+	Pick(routingToken string) (endpoint string, ok bool)
+}
+
+const (
+	breakerMaxFailures = 3                // 이 횟수만큼 연속 실패하면 회로를 연다
+	breakerCooldown    = 10 * time.Second // open 상태를 half-open으로 바꾸기 전 대기 시간
+)
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// circuitBreaker는 엔드포인트 하나의 건강 상태를 추적한다.
+type circuitBreaker struct {
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+}
+
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < breakerCooldown {
+			return false
+		}
+		b.state = breakerHalfOpen // 쿨다운이 지났으니 probe 하나를 흘려보낸다
+		return true
+	default:
+		return true
+	}
+}
+
+func (b *circuitBreaker) recordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.state = breakerClosed
+}
+
+func (b *circuitBreaker) recordFailure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= breakerMaxFailures {
+		b.state = breakerOpen
+		b.openedAt = time.Now()
+	}
+}
+
+// endpointPool은 현재 알려진 백엔드 목록과 각각의 서킷 브레이커/활성 연결 수를 들고 있다.
+// DNS 리프레시 고루틴과 모든 Balancer 구현체가 이 풀을 공유한다.
+type endpointPool struct {
+	mu        sync.RWMutex
+	endpoints []string
+	breakers  map[string]*circuitBreaker
+	connCount map[string]*int64
+}
+
+func newEndpointPool(seed []string) *endpointPool {
+	p := &endpointPool{
+		breakers:  make(map[string]*circuitBreaker),
+		connCount: make(map[string]*int64),
+	}
+	p.setEndpoints(seed)
+	return p
+}
+
+// setEndpoints는 DNS 리프레시 결과로 엔드포인트 목록을 갱신한다. 기존에 있던
+// 엔드포인트의 서킷 브레이커/연결 카운트 상태는 그대로 유지하되, fix: 더 이상
+// eps에 없는(IP가 바뀌어 사라진) 엔드포인트의 상태는 지워서 장기 실행 중
+// breakers/connCount 맵이 무한정 늘어나지 않게 한다.
+func (p *endpointPool) setEndpoints(eps []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.endpoints = append([]string(nil), eps...)
+
+	current := make(map[string]struct{}, len(eps))
+	for _, ep := range eps {
+		current[ep] = struct{}{}
+		if _, ok := p.breakers[ep]; !ok {
+			p.breakers[ep] = &circuitBreaker{}
+		}
+		if _, ok := p.connCount[ep]; !ok {
+			var n int64
+			p.connCount[ep] = &n
+		}
+	}
+	for ep := range p.breakers {
+		if _, ok := current[ep]; !ok {
+			delete(p.breakers, ep)
+		}
+	}
+	for ep := range p.connCount {
+		if _, ok := current[ep]; !ok {
+			delete(p.connCount, ep)
+		}
+	}
+}
+
+func (p *endpointPool) breakerFor(ep string) *circuitBreaker {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.breakers[ep]
+}
+
+func (p *endpointPool) incConn(ep string) {
+	p.mu.RLock()
+	n := p.connCount[ep]
+	p.mu.RUnlock()
+	if n != nil {
+		atomic.AddInt64(n, 1)
+	}
+}
+
+func (p *endpointPool) decConn(ep string) {
+	p.mu.RLock()
+	n := p.connCount[ep]
+	p.mu.RUnlock()
+	if n != nil {
+		atomic.AddInt64(n, -1)
+	}
+}
+
+func (p *endpointPool) connCountOf(ep string) int64 {
+	p.mu.RLock()
+	n := p.connCount[ep]
+	p.mu.RUnlock()
+	if n == nil {
+		return 0
+	}
+	return atomic.LoadInt64(n)
+}
+
+// healthy는 서킷 브레이커가 열려 있지 않은(= 다이얼을 시도해도 되는) 엔드포인트 목록이다.
+func (p *endpointPool) healthy() []string {
+	p.mu.RLock()
+	all := append([]string(nil), p.endpoints...)
+	p.mu.RUnlock()
+
+	out := all[:0]
+	for _, ep := range all {
+		if b := p.breakerFor(ep); b == nil || b.allow() {
+			out = append(out, ep)
+		}
+	}
+	return out
+}
+
+// startDNSRefresh는 interval마다 seed들을 mode(a|srv)에 따라 다시 질의해서 백엔드
+// 변경을 클라이언트 재시작 없이 풀에 반영한다 (기존엔 connectAndWork마다 해석했었음).
+func (p *endpointPool) startDNSRefresh(seeds []string, interval time.Duration, mode string, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.setEndpoints(resolveSeeds(seeds, mode))
+		case <-stop:
+			return
+		}
+	}
+}
+
+// resolveSeeds는 mode에 따라 A 레코드(resolveSeedsA) 또는 SRV 레코드
+// (resolveSeedsSRV)로 seed들을 풀어낸다.
+func resolveSeeds(seeds []string, mode string) []string {
+	if mode == "srv" {
+		return resolveSeedsSRV(seeds)
+	}
+	return resolveSeedsA(seeds)
+}
+
+// resolveSeedsA는 각 "host:port" 시드를 A 레코드 기준으로 펼친다. host가 이미
+// IP면 그대로 통과시킨다. 해석에 실패한 시드는 마지막으로 알려진 형태로 남긴다.
+func resolveSeedsA(seeds []string) []string {
+	var out []string
+	for _, seed := range seeds {
+		host, port, err := net.SplitHostPort(seed)
+		if err != nil {
+			out = append(out, seed)
+			continue
+		}
+		ips, err := net.LookupHost(host)
+		if err != nil || len(ips) == 0 {
+			out = append(out, seed)
+			continue
+		}
+		for _, ip := range ips {
+			out = append(out, net.JoinHostPort(ip, port))
+		}
+	}
+	return out
+}
+
+// resolveSeedsSRV는 각 시드를 SRV 레코드 이름(예: "_myservice._tcp.example.com")
+// 으로 취급해서 질의하고, 응답받은 타겟/포트 쌍으로 풀어낸다. service/proto를 직접
+// 지정하지 않고 전체 이름을 그대로 질의하도록 net.LookupSRV("", "", name)을 쓴다.
+// 해석에 실패한 시드는 마지막으로 알려진 형태로 남긴다.
+func resolveSeedsSRV(seeds []string) []string {
+	var out []string
+	for _, seed := range seeds {
+		_, records, err := net.LookupSRV("", "", seed)
+		if err != nil || len(records) == 0 {
+			out = append(out, seed)
+			continue
+		}
+		for _, rec := range records {
+			target := strings.TrimSuffix(rec.Target, ".")
+			out = append(out, net.JoinHostPort(target, fmt.Sprintf("%d", rec.Port)))
+		}
+	}
+	return out
+}
+
+// RandomBalancer: 기존 동작(serverList[rand.Intn(...)])과 동일하되, 건강한
+// 엔드포인트 중에서만 고른다.
+type RandomBalancer struct {
+	pool *endpointPool
+}
+
+func NewRandomBalancer(pool *endpointPool) *RandomBalancer {
+	return &RandomBalancer{pool: pool}
+}
+
+func (b *RandomBalancer) Pick(string) (string, bool) {
+	eps := b.pool.healthy()
+	if len(eps) == 0 {
+		return "", false
+	}
+	return eps[rand.Intn(len(eps))], true
+}
+
+// RoundRobinBalancer는 건강한 엔드포인트를 순서대로 돌아가며 고른다.
+type RoundRobinBalancer struct {
+	pool *endpointPool
+	next uint64
+}
+
+func NewRoundRobinBalancer(pool *endpointPool) *RoundRobinBalancer {
+	return &RoundRobinBalancer{pool: pool}
+}
+
+func (b *RoundRobinBalancer) Pick(string) (string, bool) {
+	eps := b.pool.healthy()
+	if len(eps) == 0 {
+		return "", false
+	}
+	i := atomic.AddUint64(&b.next, 1)
+	return eps[int(i)%len(eps)], true
+}
+
+// LeastConnBalancer는 활성 연결 수가 가장 적은 건강한 엔드포인트를 고른다.
+type LeastConnBalancer struct {
+	pool *endpointPool
+}
+
+func NewLeastConnBalancer(pool *endpointPool) *LeastConnBalancer {
+	return &LeastConnBalancer{pool: pool}
+}
+
+func (b *LeastConnBalancer) Pick(string) (string, bool) {
+	eps := b.pool.healthy()
+	if len(eps) == 0 {
+		return "", false
+	}
+	best := eps[0]
+	bestCount := b.pool.connCountOf(best)
+	for _, ep := range eps[1:] {
+		if c := b.pool.connCountOf(ep); c < bestCount {
+			best, bestCount = ep, c
+		}
+	}
+	return best, true
+}
+
+// ConsistentHashBalancer는 호출자가 준 라우팅 토큰(세션 ID 등)을 해시 링에 매핑해서
+// 같은 토큰이면 엔드포인트 집합이 바뀌지 않는 한 항상 같은 백엔드로 보낸다.
+type ConsistentHashBalancer struct {
+	pool         *endpointPool
+	virtualNodes int
+}
+
+func NewConsistentHashBalancer(pool *endpointPool) *ConsistentHashBalancer {
+	return &ConsistentHashBalancer{pool: pool, virtualNodes: 100}
+}
+
+func hashKey(s string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(s))
+	return h.Sum32()
+}
+
+func (b *ConsistentHashBalancer) Pick(routingToken string) (string, bool) {
+	eps := b.pool.healthy()
+	if len(eps) == 0 {
+		return "", false
+	}
+	if routingToken == "" {
+		// 라우팅 토큰이 없으면 균등 분산을 위해 랜덤 선택으로 대체한다.
+		return eps[rand.Intn(len(eps))], true
+	}
+
+	type ringEntry struct {
+		hash     uint32
+		endpoint string
+	}
+	ring := make([]ringEntry, 0, len(eps)*b.virtualNodes)
+	for _, ep := range eps {
+		for v := 0; v < b.virtualNodes; v++ {
+			ring = append(ring, ringEntry{hash: hashKey(fmt.Sprintf("%s#%d", ep, v)), endpoint: ep})
+		}
+	}
+	sort.Slice(ring, func(i, j int) bool { return ring[i].hash < ring[j].hash })
+
+	target := hashKey(routingToken)
+	idx := sort.Search(len(ring), func(i int) bool { return ring[i].hash >= target })
+	if idx == len(ring) {
+		idx = 0
+	}
+	return ring[idx].endpoint, true
+}
+
+// newBalancer는 -lb 플래그 값을 Balancer 구현체로 변환한다.
+func newBalancer(name string, pool *endpointPool) (Balancer, error) {
+	switch name {
+	case "", "random":
+		return NewRandomBalancer(pool), nil
+	case "round-robin":
+		return NewRoundRobinBalancer(pool), nil
+	case "least-conn":
+		return NewLeastConnBalancer(pool), nil
+	case "consistent-hash":
+		return NewConsistentHashBalancer(pool), nil
+	default:
+		return nil, fmt.Errorf("unknown -lb %q (want random|round-robin|least-conn|consistent-hash)", name)
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRoundRobinBalancerWrapsAround(t *testing.T) {
+	pool := newEndpointPool([]string{"a:1", "b:1", "c:1"})
+	b := NewRoundRobinBalancer(pool)
+
+	var picks []string
+	for i := 0; i < 6; i++ {
+		ep, ok := b.Pick("")
+		if !ok {
+			t.Fatalf("pick %d: expected ok", i)
+		}
+		picks = append(picks, ep)
+	}
+
+	// Over 6 picks across 3 endpoints, each one should show up exactly twice,
+	// and consecutive picks should cycle rather than repeat the same endpoint.
+	counts := map[string]int{}
+	for i, ep := range picks {
+		counts[ep]++
+		if i > 0 && ep == picks[i-1] {
+			t.Fatalf("round robin picked the same endpoint twice in a row at index %d: %v", i, picks)
+		}
+	}
+	for _, ep := range pool.endpoints {
+		if counts[ep] != 2 {
+			t.Fatalf("expected endpoint %s to be picked twice over a full cycle, got %d (%v)", ep, counts[ep], picks)
+		}
+	}
+}
+
+func TestLeastConnBalancerPicksFewestConnections(t *testing.T) {
+	pool := newEndpointPool([]string{"a:1", "b:1", "c:1"})
+	pool.incConn("a:1")
+	pool.incConn("a:1")
+	pool.incConn("b:1")
+
+	b := NewLeastConnBalancer(pool)
+	ep, ok := b.Pick("")
+	if !ok {
+		t.Fatal("expected ok")
+	}
+	if ep != "c:1" {
+		t.Fatalf("expected least-conn to pick c:1 (0 conns), got %s", ep)
+	}
+}
+
+func TestLeastConnBalancerTieBreaksToFirstHealthy(t *testing.T) {
+	pool := newEndpointPool([]string{"a:1", "b:1"})
+	// Both start at 0 connections - a tie. The implementation should
+	// deterministically pick the first endpoint in pool order.
+	b := NewLeastConnBalancer(pool)
+	ep, ok := b.Pick("")
+	if !ok {
+		t.Fatal("expected ok")
+	}
+	if ep != "a:1" {
+		t.Fatalf("expected tie to break to the first endpoint a:1, got %s", ep)
+	}
+}
+
+func TestConsistentHashBalancerIsStableForSameToken(t *testing.T) {
+	pool := newEndpointPool([]string{"a:1", "b:1", "c:1"})
+	b := NewConsistentHashBalancer(pool)
+
+	first, ok := b.Pick("session-42")
+	if !ok {
+		t.Fatal("expected ok")
+	}
+	for i := 0; i < 20; i++ {
+		ep, ok := b.Pick("session-42")
+		if !ok || ep != first {
+			t.Fatalf("consistent hash should always route session-42 to %s, got %s (ok=%v)", first, ep, ok)
+		}
+	}
+}
+
+func TestConsistentHashBalancerEmptyTokenFallsBack(t *testing.T) {
+	pool := newEndpointPool([]string{"a:1"})
+	b := NewConsistentHashBalancer(pool)
+	ep, ok := b.Pick("")
+	if !ok || ep != "a:1" {
+		t.Fatalf("expected fallback pick to return the only endpoint, got %s (ok=%v)", ep, ok)
+	}
+}
+
+func TestBalancersReturnNotOkWhenNoHealthyEndpoints(t *testing.T) {
+	pool := newEndpointPool(nil)
+	for name, b := range map[string]Balancer{
+		"random":      NewRandomBalancer(pool),
+		"round-robin": NewRoundRobinBalancer(pool),
+		"least-conn":  NewLeastConnBalancer(pool),
+		"consistent":  NewConsistentHashBalancer(pool),
+	} {
+		if _, ok := b.Pick("token"); ok {
+			t.Fatalf("%s: expected ok=false with no endpoints", name)
+		}
+	}
+}
+
+func TestCircuitBreakerOpensAfterMaxFailures(t *testing.T) {
+	b := &circuitBreaker{}
+	for i := 0; i < breakerMaxFailures; i++ {
+		if !b.allow() {
+			t.Fatalf("breaker should allow while still closed (failure %d)", i)
+		}
+		b.recordFailure()
+	}
+	if b.allow() {
+		t.Fatal("breaker should be open and deny requests after breakerMaxFailures consecutive failures")
+	}
+}
+
+func TestCircuitBreakerHalfOpensAfterCooldown(t *testing.T) {
+	b := &circuitBreaker{state: breakerOpen, openedAt: time.Now().Add(-breakerCooldown - time.Millisecond)}
+	if !b.allow() {
+		t.Fatal("breaker should allow a probe request once the cooldown has elapsed")
+	}
+	b.mu.Lock()
+	state := b.state
+	b.mu.Unlock()
+	if state != breakerHalfOpen {
+		t.Fatalf("expected breaker to transition to half-open, got state %v", state)
+	}
+}
+
+func TestCircuitBreakerHalfOpenFailureReopens(t *testing.T) {
+	b := &circuitBreaker{state: breakerHalfOpen}
+	b.recordFailure()
+	b.mu.Lock()
+	state := b.state
+	b.mu.Unlock()
+	if state != breakerOpen {
+		t.Fatalf("expected a half-open probe failure to reopen the breaker, got state %v", state)
+	}
+}
+
+func TestCircuitBreakerSuccessClosesBreaker(t *testing.T) {
+	b := &circuitBreaker{state: breakerHalfOpen, failures: 2}
+	b.recordSuccess()
+	b.mu.Lock()
+	state, failures := b.state, b.failures
+	b.mu.Unlock()
+	if state != breakerClosed || failures != 0 {
+		t.Fatalf("expected recordSuccess to close the breaker and reset failures, got state=%v failures=%d", state, failures)
+	}
+}
+
+// TestSetEndpointsPrunesStaleEntries is a regression test: breaker/connCount
+// entries for endpoints that drop out of a DNS refresh must be removed, not
+// leaked for the lifetime of the process.
+func TestSetEndpointsPrunesStaleEntries(t *testing.T) {
+	pool := newEndpointPool([]string{"a:1", "b:1"})
+	pool.incConn("a:1")
+
+	pool.setEndpoints([]string{"b:1", "c:1"})
+
+	pool.mu.RLock()
+	_, hasA := pool.breakers["a:1"]
+	_, hasAConn := pool.connCount["a:1"]
+	_, hasB := pool.breakers["b:1"]
+	_, hasC := pool.connCount["c:1"]
+	pool.mu.RUnlock()
+
+	if hasA || hasAConn {
+		t.Fatal("expected a:1's breaker/connCount entries to be pruned once it left the endpoint set")
+	}
+	if !hasB {
+		t.Fatal("expected b:1's breaker entry to survive since it's still in the endpoint set")
+	}
+	if !hasC {
+		t.Fatal("expected c:1 to get a fresh connCount entry")
+	}
+}
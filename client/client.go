@@ -3,26 +3,59 @@ package main
 
 import (
 	"bufio"
+	"crypto/tls"
 	"flag"
 	"fmt"
 	"math/rand"
 	"net"
+	"sync/atomic"
 	"time"
 )
 
 var (
-	// [Best #8] 클라이언트 사이드 로드 밸런싱 (목록)
+	// [Best #8] 클라이언트 사이드 로드 밸런싱 (시드 목록, DNS 리프레시로 갱신됨)
 	serverList  = []string{"localhost:9000"}
 	connTimeout = 5 * time.Second
+
+	codecName = flag.String("codec", "line", "Framing codec: line|length-prefixed|tlv") // [Best #18]
+
+	lbName             = flag.String("lb", "random", "Balancer: random|round-robin|least-conn|consistent-hash") // [Best #21]
+	routingToken       = flag.String("routing-token", "", "Routing key for -lb=consistent-hash")                // [Best #21]
+	dnsRefreshInterval = flag.Duration("dns-refresh-interval", 30*time.Second, "How often to re-resolve server endpoints")
+	dnsResolveMode     = flag.String("dns-resolve-mode", "a", "DNS refresh lookup type: a|srv") // [Best #21]
 )
 
+// codec은 -codec 플래그로 고른 프레이밍 구현체. 서버와 동일한 값을 줘야 한다.
+var codec Codec
+
 func main() {
 	flag.Parse()
 	rand.Seed(time.Now().UnixNano())
 
+	var err error
+	codec, err = newCodec(*codecName)
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		return
+	}
+
+	// [Best #23] -metrics-addr가 설정되어 있으면 하트비트 RTT를 Prometheus로 노출
+	startMetricsServer()
+
+	// [Best #21] 엔드포인트 풀 + 서킷 브레이커 + 백그라운드 DNS 리프레시.
+	// 기존엔 connectAndWork마다 ResolveTCPAddr를 호출했지만, 이제는 별도 고루틴이
+	// 주기적으로 풀을 갱신해서 연결 시도 경로에서 DNS 지연을 없앤다.
+	pool := newEndpointPool(serverList)
+	balancer, err := newBalancer(*lbName, pool)
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		return
+	}
+	go pool.startDNSRefresh(serverList, *dnsRefreshInterval, *dnsResolveMode, nil)
+
 	// 무한 재접속 루프
 	for {
-		connectAndWork()
+		connectAndWork(pool, balancer)
 
 		// [Best #6] 연결 끊기면 바로 붙지 않고 Backoff + Jitter 적용
 		// 1초 ~ 3초 사이 랜덤 대기 (Thundering Herd 방지)
@@ -32,30 +65,63 @@ func main() {
 	}
 }
 
-func connectAndWork() {
-	// [Best #8] 서버 목록 중 랜덤 선택 (Simple LB)
-	target := serverList[rand.Intn(len(serverList))]
-
-	// [Best #11] DNS 갱신 (ResolveTCPAddr를 매번 호출)
-	// IP가 바뀌었을 경우를 대비해 연결 시마다 다시 해석함
-	tcpAddr, err := net.ResolveTCPAddr("tcp", target)
-	if err != nil {
-		fmt.Printf("DNS Resolve failed: %v\n", err)
+func connectAndWork(pool *endpointPool, balancer Balancer) {
+	// [Best #21] 건강한 엔드포인트 중에서 Balancer 전략에 따라 선택
+	target, ok := balancer.Pick(*routingToken)
+	if !ok {
+		fmt.Println("No healthy endpoints available")
 		return
 	}
+	breaker := pool.breakerFor(target)
 
 	// [Best #4] 연결 타임아웃 설정
-	conn, err := net.DialTimeout("tcp", tcpAddr.String(), connTimeout)
+	conn, err := net.DialTimeout("tcp", target, connTimeout)
 	if err != nil {
 		fmt.Printf("Connection failed: %v\n", err)
+		if breaker != nil {
+			breaker.recordFailure()
+		}
 		return
 	}
+
+	// [Best #19] -tls가 켜져 있으면 평문 연결 위에 TLS 핸드셰이크를 얹는다.
+	tlsConfig, err := buildClientTLSConfig()
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		conn.Close()
+		return
+	}
+	if tlsConfig != nil {
+		host, _, splitErr := net.SplitHostPort(target)
+		if splitErr == nil {
+			tlsConfig.ServerName = host
+		}
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			fmt.Printf("TLS handshake failed: %v\n", err)
+			conn.Close()
+			if breaker != nil {
+				breaker.recordFailure()
+			}
+			return
+		}
+		conn = tlsConn
+	}
 	defer conn.Close()
 
+	if breaker != nil {
+		breaker.recordSuccess()
+	}
+	pool.incConn(target)
+	defer pool.decConn(target)
+
 	fmt.Printf("✅ Connected to %s\n", target)
 
-	// [Best #5] 하트비트 루프 (별도 고루틴)
-	// 서버가 죽었는지 살았는지 능동적으로 체크
+	writer := bufio.NewWriter(conn)
+
+	// [Best #5, #23] 하트비트 루프 (별도 고루틴): 서버 생존 확인 + PING 전송 시각을
+	// 기록해서 PONG을 받는 메인 루프에서 RTT를 히스토그램으로 관측한다.
+	var lastPingSentAt atomic.Value // time.Time
 	stopHeartbeat := make(chan struct{})
 	go func() {
 		ticker := time.NewTicker(10 * time.Second)
@@ -65,10 +131,13 @@ func connectAndWork() {
 			case <-ticker.C:
 				// PING 전송
 				conn.SetWriteDeadline(time.Now().Add(2 * time.Second))
-				_, err := fmt.Fprintf(conn, "PING\n")
-				if err != nil {
+				lastPingSentAt.Store(time.Now())
+				if err := codec.WriteFrame(writer, []byte("PING")); err != nil {
 					return // 에러 나면 루프 종료
 				}
+				if err := writer.Flush(); err != nil {
+					return
+				}
 			case <-stopHeartbeat:
 				return
 			}
@@ -80,19 +149,21 @@ func connectAndWork() {
 	for {
 		// 서버 응답 대기 (여기서도 ReadDeadline 필요하면 설정)
 		conn.SetReadDeadline(time.Now().Add(65 * time.Second)) // 서버 하트비트(60s) 고려
-		msg, err := reader.ReadString('\n')
+		frame, err := codec.ReadFrame(reader)
 		if err != nil {
 			fmt.Printf("Disconnected: %v\n", err)
 			close(stopHeartbeat) // 하트비트 중단
 			return               // 함수 리턴 -> 재접속 대기(Backoff)로 이동
 		}
 
-		// PONG 응답은 로그만 찍고 무시
-		if msg == "PONG\n" {
-			// fmt.Println("Received PONG")
+		// PONG 응답은 로그만 찍고 무시 (단, RTT는 관측한다)
+		if string(frame) == "PONG" {
+			if sentAt, ok := lastPingSentAt.Load().(time.Time); ok {
+				heartbeatRTT.observe(time.Since(sentAt).Seconds())
+			}
 			continue
 		}
 
-		fmt.Print("Server: " + msg)
+		fmt.Printf("Server: %s\n", frame)
 	}
 }
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+)
+
+// [Best #23] 서버와 대칭을 이루는 Prometheus 텍스트 노출. 클라이언트는 주로
+// PING→PONG 하트비트 RTT를 추적하는 데 쓴다.
+type histogram struct {
+	mu      sync.Mutex
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+func (h *histogram) observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sum += v
+	h.count++
+	for i, b := range h.buckets {
+		if v <= b {
+			h.counts[i]++
+		}
+	}
+}
+
+func (h *histogram) writeTo(w http.ResponseWriter, name, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, b := range h.buckets {
+		fmt.Fprintf(w, "%s_bucket{le=\"%g\"} %d\n", name, b, h.counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", name, h.count)
+	fmt.Fprintf(w, "%s_sum %g\n", name, h.sum)
+	fmt.Fprintf(w, "%s_count %d\n", name, h.count)
+}
+
+// heartbeatRTT는 PING을 보낸 시점부터 대응하는 PONG을 받을 때까지 걸린 시간이다.
+var heartbeatRTT = newHistogram([]float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5})
+
+var metricsAddr = flag.String("metrics-addr", "", "Address to serve Prometheus /metrics on (empty disables it)")
+
+func startMetricsServer() {
+	if *metricsAddr == "" {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		heartbeatRTT.writeTo(w, "tcp_client_heartbeat_rtt_seconds", "Round-trip time between a PING and its matching PONG")
+	})
+	go func() {
+		log.Printf("📈 Metrics server on %s", *metricsAddr)
+		if err := http.ListenAndServe(*metricsAddr, mux); err != nil {
+			log.Printf("metrics server failed: %v", err)
+		}
+	}()
+}
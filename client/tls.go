@@ -0,0 +1,49 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// [Best #19] 서버의 TLS/mTLS 지원과 대칭을 이루는 클라이언트 측 플래그.
+var (
+	tlsEnabled  = flag.Bool("tls", false, "Connect using TLS")
+	tlsCAFile   = flag.String("ca", "", "PEM CA bundle to verify the server cert")
+	tlsCertFile = flag.String("cert", "", "Client certificate file (PEM), for mTLS")
+	tlsKeyFile  = flag.String("key", "", "Client private key file (PEM), for mTLS")
+)
+
+// buildClientTLSConfig는 -tls가 꺼져 있으면 nil을 돌려줘서 호출자가 평문
+// net.Dial을 쓰게 한다.
+func buildClientTLSConfig() (*tls.Config, error) {
+	if !*tlsEnabled {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{}
+
+	if *tlsCAFile != "" {
+		caPEM, err := os.ReadFile(*tlsCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls: read CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("tls: no certs parsed from %s", *tlsCAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if *tlsCertFile != "" || *tlsKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(*tlsCertFile, *tlsKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls: load client keypair: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// [Best #18] 프레이밍 추상화: ReadString('\n')에 박혀 있던 라인 단위 프레이밍을
+// Codec 인터페이스로 뽑아내어, 바이너리 프로토콜도 핸들러 재작성 없이 돌릴 수 있게 한다.
+const maxFrameSize = 1 << 20 // 1MiB 프레임 상한 (조작된 길이값으로 인한 OOM 방지)
+
+// ErrFrameTooLarge는 길이 프리픽스가 maxFrameSize를 초과할 때 반환된다.
+var ErrFrameTooLarge = errors.New("codec: frame exceeds max frame size")
+
+// Codec은 bufio.Reader/Writer 위에서 프레임 단위 읽기/쓰기를 수행한다.
+type Codec interface {
+	ReadFrame(r *bufio.Reader) ([]byte, error)
+	WriteFrame(w *bufio.Writer, payload []byte) error
+}
+
+// LineCodec은 기존 동작과 동일한 '\n' 구분 텍스트 프레이밍이다.
+type LineCodec struct{}
+
+func (LineCodec) ReadFrame(r *bufio.Reader) ([]byte, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	return []byte(strings.TrimRight(line, "\r\n")), nil
+}
+
+func (LineCodec) WriteFrame(w *bufio.Writer, payload []byte) error {
+	if _, err := w.Write(payload); err != nil {
+		return err
+	}
+	return w.WriteByte('\n')
+}
+
+// LengthPrefixedCodec은 uint32 빅엔디안 길이 + payload 구조로, 흔히 쓰는
+// PkgLen|HeadLen|Head|Body 스킴에서 Head를 생략한 최소 형태에 해당한다.
+type LengthPrefixedCodec struct{}
+
+func (LengthPrefixedCodec) ReadFrame(r *bufio.Reader) ([]byte, error) {
+	var lenBuf [4]byte
+	if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(lenBuf[:])
+	if n > maxFrameSize {
+		return nil, ErrFrameTooLarge
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+func (LengthPrefixedCodec) WriteFrame(w *bufio.Writer, payload []byte) error {
+	var lenBuf [4]byte
+	binary.BigEndian.PutUint32(lenBuf[:], uint32(len(payload)))
+	if _, err := w.Write(lenBuf[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+// TLVCodec은 Type(1바이트) + Length(uint32 BE) + Value 구조이다. Type은 데이터
+// 프레임을 나타내는 고정 태그로, 향후 제어 프레임(예: 하트비트)을 추가할 여지를 남긴다.
+type TLVCodec struct{}
+
+const tlvTypeData byte = 0x01
+
+func (TLVCodec) ReadFrame(r *bufio.Reader) ([]byte, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	var lenBuf [4]byte
+	if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(lenBuf[:])
+	if n > maxFrameSize {
+		return nil, ErrFrameTooLarge
+	}
+	value := make([]byte, n)
+	if _, err := io.ReadFull(r, value); err != nil {
+		return nil, err
+	}
+	if tag != tlvTypeData {
+		return nil, fmt.Errorf("codec: tlv unexpected type %#x (want %#x)", tag, tlvTypeData)
+	}
+	return value, nil
+}
+
+func (TLVCodec) WriteFrame(w *bufio.Writer, payload []byte) error {
+	if err := w.WriteByte(tlvTypeData); err != nil {
+		return err
+	}
+	var lenBuf [4]byte
+	binary.BigEndian.PutUint32(lenBuf[:], uint32(len(payload)))
+	if _, err := w.Write(lenBuf[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+// newCodec은 -codec 플래그 값을 Codec 구현체로 변환한다.
+func newCodec(name string) (Codec, error) {
+	switch name {
+	case "", "line":
+		return LineCodec{}, nil
+	case "length-prefixed":
+		return LengthPrefixedCodec{}, nil
+	case "tlv":
+		return TLVCodec{}, nil
+	default:
+		return nil, fmt.Errorf("unknown -codec %q (want line|length-prefixed|tlv)", name)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"crypto/tls"
+	"expvar"
+	"flag"
+	"log"
+	"net"
+	"time"
+)
+
+// [Best #20] 소켓 레벨 튜닝: accept 직후 커널 소켓 옵션을 손대서 지연/처리량 특성을
+// 워크로드에 맞게 조정한다. 기본값은 기존 동작(OS 기본 버퍼, Nagle 비활성)과 같다.
+var (
+	tcpNoDelay         = flag.Bool("tcp-nodelay", true, "Disable Nagle's algorithm on accepted sockets")
+	tcpKeepAlivePeriod = flag.Duration("tcp-keepalive-period", 30*time.Second, "TCP keepalive probe interval")
+	tcpRcvBuf          = flag.Int("tcp-rcvbuf", 0, "SO_RCVBUF size in bytes (0 = OS default)")
+	tcpSndBuf          = flag.Int("tcp-sndbuf", 0, "SO_SNDBUF size in bytes (0 = OS default)")
+)
+
+func init() {
+	// [Best #20] 실제 적용된 값을 /debug/vars에서 그대로 확인할 수 있게 한다.
+	expvar.Publish("tcp_tune_settings", expvar.Func(func() interface{} {
+		return map[string]interface{}{
+			"nodelay":          *tcpNoDelay,
+			"keepalive_period": tcpKeepAlivePeriod.String(),
+			"rcvbuf":           *tcpRcvBuf,
+			"sndbuf":           *tcpSndBuf,
+		}
+	}))
+}
+
+// unwrapTCPConn은 net.Conn 체인을 타고 내려가 기반이 되는 *net.TCPConn을 찾는다.
+// TLS로 감싸인 연결(*tls.Conn)도 NetConn()을 통해 풀어낸다.
+func unwrapTCPConn(conn net.Conn) (*net.TCPConn, bool) {
+	switch c := conn.(type) {
+	case *net.TCPConn:
+		return c, true
+	case *tls.Conn:
+		return unwrapTCPConn(c.NetConn())
+	default:
+		return nil, false
+	}
+}
+
+// tuneConn은 accept된 연결에 소켓 옵션을 적용한다. TLS 래핑 등으로 기반 TCP 연결에
+// 닿지 못하면 경고만 남기고 계속 진행한다 (치명적인 실패가 아님).
+func tuneConn(conn net.Conn) {
+	tcpConn, ok := unwrapTCPConn(conn)
+	if !ok {
+		log.Printf("tuneConn: %s is not backed by a *net.TCPConn, skipping socket tuning", conn.RemoteAddr())
+		return
+	}
+
+	if err := tcpConn.SetNoDelay(*tcpNoDelay); err != nil {
+		log.Printf("tuneConn: SetNoDelay failed: %v", err)
+	}
+	if err := tcpConn.SetKeepAlive(true); err != nil {
+		log.Printf("tuneConn: SetKeepAlive failed: %v", err)
+	}
+	if err := tcpConn.SetKeepAlivePeriod(*tcpKeepAlivePeriod); err != nil {
+		log.Printf("tuneConn: SetKeepAlivePeriod failed: %v", err)
+	}
+	if *tcpRcvBuf > 0 {
+		if err := tcpConn.SetReadBuffer(*tcpRcvBuf); err != nil {
+			log.Printf("tuneConn: SetReadBuffer failed: %v", err)
+		}
+	}
+	if *tcpSndBuf > 0 {
+		if err := tcpConn.SetWriteBuffer(*tcpSndBuf); err != nil {
+			log.Printf("tuneConn: SetWriteBuffer failed: %v", err)
+		}
+	}
+}
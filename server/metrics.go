@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// [Best #23] Prometheus 텍스트 노출 포맷: 이 저장소는 표준 라이브러리만 쓰는
+// 것이 지금까지의 관례라, 외부 클라이언트 라이브러리 없이 히스토그램을 직접 구현해서
+// 기존 expvar 카운터들과 나란히 /metrics에서 노출한다.
+type histogram struct {
+	mu      sync.Mutex
+	buckets []float64 // 오름차순 상한값 (+Inf는 암묵적으로 마지막에 붙는다)
+	counts  []uint64  // counts[i] = buckets[i] 이하로 관측된 누적 횟수
+	sum     float64
+	count   uint64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+func (h *histogram) observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sum += v
+	h.count++
+	for i, b := range h.buckets {
+		if v <= b {
+			h.counts[i]++
+		}
+	}
+}
+
+func (h *histogram) writeTo(w http.ResponseWriter, name, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, b := range h.buckets {
+		fmt.Fprintf(w, "%s_bucket{le=\"%g\"} %d\n", name, b, h.counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", name, h.count)
+	fmt.Fprintf(w, "%s_sum %g\n", name, h.sum)
+	fmt.Fprintf(w, "%s_count %d\n", name, h.count)
+}
+
+var (
+	connLifetimeSeconds   = newHistogram([]float64{0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60, 300})
+	requestLatencySeconds = newHistogram([]float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1})
+	bytesReadHist         = newHistogram([]float64{16, 64, 256, 1024, 4096, 16384, 65536})
+	bytesWrittenHist      = newHistogram([]float64{16, 64, 256, 1024, 4096, 16384, 65536})
+)
+
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	connLifetimeSeconds.writeTo(w, "tcp_connection_lifetime_seconds", "Connection lifetime from accept to close")
+	requestLatencySeconds.writeTo(w, "tcp_request_handling_seconds", "Time spent handling a single request frame")
+	bytesReadHist.writeTo(w, "tcp_bytes_read", "Size in bytes of frames read from clients")
+	bytesWrittenHist.writeTo(w, "tcp_bytes_written", "Size in bytes of frames written to clients")
+
+	fmt.Fprintf(w, "# HELP tcp_current_connections Current open connections\n# TYPE tcp_current_connections gauge\ntcp_current_connections %d\n", currentConns.Value())
+	fmt.Fprintf(w, "# HELP tcp_total_connections Total accepted connections\n# TYPE tcp_total_connections counter\ntcp_total_connections %d\n", totalConns.Value())
+	fmt.Fprintf(w, "# HELP tcp_timeout_errors Total read/write timeout errors\n# TYPE tcp_timeout_errors counter\ntcp_timeout_errors %d\n", timeoutErrs.Value())
+	fmt.Fprintf(w, "# HELP tcp_workers_active Workers currently handling a connection\n# TYPE tcp_workers_active gauge\ntcp_workers_active %d\n", workersActive.Value())
+	fmt.Fprintf(w, "# HELP tcp_workers_idle Workers parked in the idle pool\n# TYPE tcp_workers_idle gauge\ntcp_workers_idle %d\n", workersIdle.Value())
+	fmt.Fprintf(w, "# HELP tcp_shutdowns_total Total graceful shutdowns initiated\n# TYPE tcp_shutdowns_total counter\ntcp_shutdowns_total %d\n", shutdownsTotal.Value())
+	fmt.Fprintf(w, "# HELP tls_handshakes_total Total successful TLS handshakes\n# TYPE tls_handshakes_total counter\ntls_handshakes_total %d\n", tlsHandshakesTotal.Value())
+	fmt.Fprintf(w, "# HELP tls_handshake_errors_total Total failed TLS handshakes\n# TYPE tls_handshake_errors_total counter\ntls_handshake_errors_total %d\n", tlsHandshakeErrorsTotal.Value())
+}
@@ -3,6 +3,8 @@ package main
 import (
 	"bufio"
 	"context"
+	"crypto/tls"
+	"errors"
 	"expvar"
 	"flag"
 	"io"
@@ -13,6 +15,7 @@ import (
 	"os/signal"
 	"runtime/trace"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 )
@@ -25,6 +28,11 @@ var (
 	readTimeout    = 60 * time.Second                                         // [Best #4] 읽기 타임아웃 (좀비 방지)
 	writeTimeout   = 5 * time.Second                                          // [Best #4] 쓰기 타임아웃 (블로킹 방지)
 	maxConnAge     = 1 * time.Hour                                            // [Best #10] 연결 TTL (로드밸런싱 리밸런싱 유도)
+
+	workersCount      = flag.Int("workers", 256, "Max long-lived worker goroutines in the reactor pool")                       // [Best #17]
+	workerIdleTimeout = flag.Duration("worker-idle-timeout", 10*time.Second, "Idle workers exit after this long without work") // [Best #17]
+
+	codecName = flag.String("codec", "line", "Framing codec: line|length-prefixed|tlv") // [Best #18]
 )
 
 // [Best #16] 가시성 확보: expvar를 통한 실시간 메트릭 노출 (/debug/vars)
@@ -32,11 +40,31 @@ var (
 	currentConns = expvar.NewInt("tcp_current_connections")
 	totalConns   = expvar.NewInt("tcp_total_connections")
 	timeoutErrs  = expvar.NewInt("tcp_timeout_errors")
+
+	workersActive = expvar.NewInt("tcp_workers_active") // [Best #17] 현재 연결을 처리 중인 워커 수
+	workersIdle   = expvar.NewInt("tcp_workers_idle")   // [Best #17] 유휴 스택에서 대기 중인 워커 수
+)
+
+// codec은 -codec 플래그로 고른 프레이밍 구현체. main()에서 한 번 결정되어 모든
+// 연결의 handleConnection이 공유한다.
+var codec Codec
+
+// shutdownCtx는 모든 연결의 상위 컨텍스트다. gracefulShutdown이 취소하면
+// maxConnAge 타임아웃과 동일한 경로로 각 연결의 읽기 루프가 빠져나간다.
+var (
+	shutdownCtx    context.Context
+	shutdownCancel context.CancelFunc
 )
 
 func main() {
 	flag.Parse()
 
+	var err error
+	codec, err = newCodec(*codecName)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
 	// [Trace] 성능 분석을 위한 추적 시작
 	f, err := os.Create("trace.out")
 	if err != nil {
@@ -48,35 +76,80 @@ func main() {
 	}
 	defer trace.Stop()
 
-	// [Best #15] 우아한 종료 (Graceful Shutdown): 시그널 핸들링
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-	go func() {
-		<-sigChan
-		log.Println("\n🛑 Shutting down... saving trace.")
-		trace.Stop() // 추적 데이터 저장 보장
-		f.Close()
-		os.Exit(0)
-	}()
-
 	// [Best #3] 관리 포트 분리: 서비스 포트가 막혀도 모니터링 가능하도록 함
-	go startAdminServer(*adminPort)
+	adminSrv := startAdminServer(*adminPort)
 
 	// [Best #1, #2] 포트 바인딩: Go는 SO_REUSEADDR 기본 적용, 포트 규격 준수
 	ln, err := net.Listen("tcp", ":"+*listenPort)
 	if err != nil {
 		log.Fatalf("Failed to bind: %v", err)
 	}
-	defer ln.Close()
 
-	log.Printf("🛡️ Server on :%s (MaxConn: %d)", *listenPort, *maxConnections)
+	// [Best #19] -tls-cert가 설정되어 있으면 평문 리스너를 TLS로 감싼다.
+	tlsConfig, err := buildTLSConfig()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if tlsConfig != nil {
+		ln = tls.NewListener(ln, tlsConfig)
+		log.Printf("🔐 TLS enabled (min version %s, mTLS: %v)", *tlsMinVerStr, *tlsClientCA != "")
+	}
+
+	log.Printf("🛡️ Server on :%s (MaxConn: %d, Workers: %d)", *listenPort, *maxConnections, *workersCount)
 
 	// [Best #7] 과부하 방지 (Backpressure): 세마포어 패턴 사용
 	sem := make(chan struct{}, *maxConnections)
 
+	// [Best #22] 모든 연결의 상위 컨텍스트. gracefulShutdown이 취소하면 활성
+	// 연결들이 현재 프레임을 끝낸 뒤 빠져나간다. connWG는 드레인 완료를 기다리는 데 쓴다.
+	shutdownCtx, shutdownCancel = context.WithCancel(context.Background())
+	var connWG sync.WaitGroup
+
+	// [Best #17] 리액터 패턴: accept마다 새 고루틴을 만드는 대신 워커 풀에 넘긴다.
+	// WorkerFunc는 핸들러 실행 후 세마포어 슬롯을 반납하는 책임까지 진다.
+	pool := &workerPool{
+		MaxWorkersCount: *workersCount,
+		IdleTimeout:     *workerIdleTimeout,
+	}
+	pool.WorkerFunc = func(conn net.Conn) {
+		defer connWG.Done()
+		handleConnection(conn)
+		<-sem // 작업 완료 후 슬롯 반납
+		currentConns.Add(-1)
+	}
+	pool.Start()
+
+	// [Best #22] fix: accept 루프는 반드시 별도 고루틴에서 돌려야 한다. 이전엔
+	// main() 본문에서 직접 for{}를 돌렸는데, gracefulShutdown이 ln.Close()를
+	// 부르면 이 루프의 errors.Is(err, net.ErrClosed) 분기가 "return"으로 main()
+	// 자체를 끝내버렸다 - Go에서 main()이 반환하면 다른 고루틴을 기다리지 않고
+	// 프로세스가 즉시 죽는다. 그 결과 드레인 대기("all connections drained"
+	// 로그나 -shutdown-timeout 대기)가 시작되기도 전에 프로세스가 종료됐다.
+	// 이제 accept 루프는 자신만의 고루틴에서 끝나고, main()은 시그널을 받은 뒤
+	// gracefulShutdown이 끝날 때까지 직접 블로킹한다.
+	go acceptLoop(ln, pool, sem, &connWG)
+
+	setReady(true)
+
+	// [Best #15, #22] 시그널을 받으면 드레인을 마친 뒤에 main()이 반환되도록
+	// 그 자체를 블로킹 지점으로 둔다 (os.Exit로 강제 종료하지 않음).
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	<-sigChan
+
+	gracefulShutdown(ln, pool, adminSrv, shutdownCancel, &connWG)
+	// trace.Stop()/f.Close()는 위에서 defer로 등록했으므로 main()이 반환되며 실행된다.
+}
+
+// acceptLoop는 연결을 받아 워커 풀에 넘긴다. gracefulShutdown이 ln을 닫으면
+// net.ErrClosed를 받고 이 고루틴만 끝난다 (main()은 영향받지 않는다).
+func acceptLoop(ln net.Listener, pool *workerPool, sem chan struct{}, connWG *sync.WaitGroup) {
 	for {
 		conn, err := ln.Accept()
 		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
 			if ne, ok := err.(net.Error); ok && ne.Temporary() {
 				time.Sleep(10 * time.Millisecond)
 				continue
@@ -85,17 +158,23 @@ func main() {
 			continue
 		}
 
+		// [Best #20] 커널 소켓 옵션 튜닝: 용량 체크보다 먼저, 매 연결에 적용
+		tuneConn(conn)
+
 		// 연결 수락 전 용량 체크 (Non-blocking)
 		select {
 		case sem <- struct{}{}:
-			// 슬롯 확보 성공 -> 고루틴 실행
+			// 슬롯 확보 성공 -> 워커 풀에 핸드오프
 			currentConns.Add(1)
 			totalConns.Add(1)
-			go func() {
-				handleConnection(conn)
-				<-sem // 작업 완료 후 슬롯 반납
+			connWG.Add(1)
+			if !pool.Serve(conn) {
+				// 풀의 워커가 전부 사용 중이면 슬롯을 반납하고 거절
+				conn.Close()
+				<-sem
 				currentConns.Add(-1)
-			}()
+				connWG.Done()
+			}
 		default:
 			// [Best #7] Fail Fast: 용량 초과 시 대기 없이 즉시 거절
 			conn.Close()
@@ -107,14 +186,46 @@ func handleConnection(conn net.Conn) {
 	// [Best #9] 자원 해제 보장: 함수 종료 시 소켓 닫기 (CLOSE_WAIT 방지)
 	defer conn.Close()
 
-	// [Best #10] 장기 연결 강제 종료 (TTL): 한 서버에 연결 고착화 방지
-	ctx, cancel := context.WithTimeout(context.Background(), maxConnAge)
+	// [Best #23] 연결 단위 추적 스팬 시작 + 연결 수명 히스토그램
+	connStart := time.Now()
+	connSpan := startSpan("tcp.connection", nil)
+	defer func() {
+		connLifetimeSeconds.observe(time.Since(connStart).Seconds())
+		connSpan.end(map[string]string{"peer.addr": conn.RemoteAddr().String()})
+	}()
+
+	// [Best #19] TLS 연결이면 핸드셰이크를 여기서 끝내서 실패를 바로 잡아내고,
+	// mTLS일 경우 피어 인증서 CN을 로그에 남긴다.
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		if err := tlsConn.Handshake(); err != nil {
+			tlsHandshakeErrorsTotal.Add(1)
+			log.Printf("tls handshake failed from %s: %v", conn.RemoteAddr(), err)
+			return
+		}
+		tlsHandshakesTotal.Add(1)
+		if cn := peerCertSubject(conn); cn != "" {
+			log.Printf("🔐 tls client %s authenticated as CN=%s", conn.RemoteAddr(), cn)
+		}
+	}
+
+	// [Best #10, #22] 장기 연결 강제 종료 (TTL) + 종료 브로드캐스트: shutdownCtx를
+	// 부모로 둬서, gracefulShutdown이 취소하든 TTL이 만료되든 같은 경로로 빠져나간다.
+	ctx, cancel := context.WithTimeout(shutdownCtx, maxConnAge)
 	defer cancel()
 
 	go func() {
 		<-ctx.Done()
-		if ctx.Err() == context.DeadlineExceeded {
-			conn.SetReadDeadline(time.Now()) // 강제로 IO 에러 유발하여 연결 끊기
+		if ctx.Err() != nil {
+			conn.SetReadDeadline(time.Now()) // 강제로 IO 에러 유발하여 연결 끊기 (현재 프레임은 이미 처리 중)
+		}
+	}()
+
+	// [Best #22] 드레인 중 종료된 연결이면 소요 시간을 로그로 남긴다.
+	defer func() {
+		if shutdownCtx.Err() != nil {
+			if startedAt, ok := shutdownStartedAt.Load().(time.Time); ok {
+				log.Printf("🧹 drained %s in %v", conn.RemoteAddr(), time.Since(startedAt))
+			}
 		}
 	}()
 
@@ -126,7 +237,7 @@ func handleConnection(conn net.Conn) {
 		// [Best #4] 타임아웃 설정: 좀비 커넥션 및 Slowloris 공격 방어
 		conn.SetReadDeadline(time.Now().Add(readTimeout))
 
-		line, err := reader.ReadString('\n')
+		frame, err := codec.ReadFrame(reader)
 		if err != nil {
 			if err != io.EOF {
 				if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
@@ -135,30 +246,75 @@ func handleConnection(conn net.Conn) {
 			}
 			return
 		}
+		bytesReadHist.observe(float64(len(frame)))
+
+		// [Best #23] 요청 프레임 하나당 스팬 + 처리 지연 히스토그램. length-prefixed
+		// 코덱을 쓸 때는 프레임 앞에 묻어온 trace ID를 이어받아 부모-자식 관계를 유지한다.
+		reqStart := time.Now()
+		traceID, frame := extractTraceID(frame)
+		reqSpan := startSpan("tcp.request", traceID)
 
-		line = strings.TrimSpace(line)
+		line := strings.TrimSpace(string(frame))
 
 		// [Best #5] 애플리케이션 하트비트: TCP Keepalive 외에 실제 서비스 생존 확인
 		if line == "PING" {
 			conn.SetWriteDeadline(time.Now().Add(writeTimeout)) // [Best #4] 쓰기 데드라인
-			writer.WriteString("PONG\n")
+			reply := injectTraceID(traceID, []byte("PONG"))
+			codec.WriteFrame(writer, reply)
 			writer.Flush()
+			bytesWrittenHist.observe(float64(len(reply)))
+			requestLatencySeconds.observe(time.Since(reqStart).Seconds())
+			reqSpan.end(map[string]string{"request.kind": "ping"})
 			continue
 		}
 
 		// 비즈니스 로직
 		conn.SetWriteDeadline(time.Now().Add(writeTimeout))
-		writer.WriteString("ECHO: " + line + "\n")
+		reply := injectTraceID(traceID, []byte("ECHO: "+line))
+		codec.WriteFrame(writer, reply)
 		writer.Flush()
+		bytesWrittenHist.observe(float64(len(reply)))
+		requestLatencySeconds.observe(time.Since(reqStart).Seconds())
+		reqSpan.end(map[string]string{"request.kind": "echo"})
 	}
 }
 
-func startAdminServer(port string) {
-	// [Best #16] 모니터링 엔드포인트 제공
-	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+func startAdminServer(port string) *http.Server {
+	mux := http.NewServeMux()
+
+	// [Best #16] 모니터링 엔드포인트 제공 (liveness: 프로세스가 살아있는지)
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
 	})
-	log.Printf("🚑 Admin Server on :%s", port)
-	log.Fatal(http.ListenAndServe(":"+port, nil))
+
+	// [Best #22] 준비 상태 엔드포인트 (readiness): 드레인 중엔 503을 돌려줘서
+	// 로드밸런서가 새 트래픽을 이 인스턴스로 보내지 않게 한다.
+	mux.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
+		if !isReady() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("NOT READY"))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("READY"))
+	})
+
+	// [Best #23] expvar 카운터를 Prometheus 포맷으로도 노출
+	mux.HandleFunc("/metrics", metricsHandler)
+
+	// fix: 관리 서버를 http.DefaultServeMux에서 전용 mux로 옮기면서 expvar가
+	// init()에서 DefaultServeMux에만 등록하는 /debug/vars 핸들러가 같이
+	// 빠졌다. tcp_workers_active/idle(Best #17)과 conntune.go의 tcp_tune_settings
+	// (Best #20)가 전부 안 보이게 된 원인이라 여기서 다시 붙인다.
+	mux.Handle("/debug/vars", expvar.Handler())
+
+	srv := &http.Server{Addr: ":" + port, Handler: mux}
+	go func() {
+		log.Printf("🚑 Admin Server on :%s", port)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("admin server failed: %v", err)
+		}
+	}()
+	return srv
 }
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestAdminServerExposesDebugVars is a regression test: moving the admin
+// server off http.DefaultServeMux (so it could support graceful Shutdown)
+// silently dropped /debug/vars, since expvar only registers its handler on
+// DefaultServeMux. It must be mounted explicitly on the admin mux.
+func TestAdminServerExposesDebugVars(t *testing.T) {
+	srv := startAdminServer("0")
+	defer srv.Close()
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/vars", nil)
+	rec := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("/debug/vars returned %d, want %d", rec.Code, http.StatusOK)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json; charset=utf-8" {
+		t.Fatalf("/debug/vars content-type = %q, want application/json", ct)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"expvar"
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// [Best #22] 우아한 종료 고도화: 기존엔 SIGTERM을 받으면 os.Exit(0)으로 즉시
+// 죽어서 처리 중인 연결이 끊기고 defer된 자원 해제도 건너뛰었다. 이제는 accept를
+// 멈추고, 준비 상태를 내려서 로드밸런서가 트래픽을 빼가게 한 뒤, 현재 프레임을
+// 끝낸 핸들러들이 자연스럽게 빠져나갈 때까지 기다린다.
+var shutdownTimeout = flag.Duration("shutdown-timeout", 15*time.Second, "Max time to wait for in-flight connections to drain on shutdown")
+
+var shutdownsTotal = expvar.NewInt("tcp_shutdowns_total")
+
+// readyFlag는 /ready가 내려다보는 준비 상태다. 1이면 Ready.
+var readyFlag int32
+
+func setReady(v bool) {
+	n := int32(0)
+	if v {
+		n = 1
+	}
+	atomic.StoreInt32(&readyFlag, n)
+}
+
+func isReady() bool {
+	return atomic.LoadInt32(&readyFlag) == 1
+}
+
+// shutdownStartedAt은 드레인 소요 시간을 로그로 남기기 위한 시작 시각이다.
+// 핸들러 고루틴들이 동시에 읽으므로 atomic.Value로 감싼다.
+var shutdownStartedAt atomic.Value
+
+// gracefulShutdown은 새 연결 수락을 멈추고, 활성 연결이 모두 빠지거나
+// -shutdown-timeout이 지날 때까지 기다린 뒤 워커 풀과 admin 서버를 정리한다.
+func gracefulShutdown(ln net.Listener, pool *workerPool, adminSrv *http.Server, cancel context.CancelFunc, connWG *sync.WaitGroup) {
+	log.Println("🛑 shutdown signal received: draining connections")
+	shutdownsTotal.Add(1)
+	shutdownStartedAt.Store(time.Now())
+	setReady(false)
+
+	ln.Close() // 새 연결 수락 중단
+	cancel()   // 활성 핸들러에게 종료를 브로드캐스트 (현재 프레임은 끝까지 처리)
+
+	drained := make(chan struct{})
+	go func() {
+		connWG.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		log.Printf("✅ all connections drained")
+	case <-time.After(*shutdownTimeout):
+		log.Printf("⏱️ shutdown timeout (%v) reached with %d connection(s) still active, forcing close", *shutdownTimeout, currentConns.Value())
+	}
+
+	pool.Stop()
+
+	httpCtx, httpCancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer httpCancel()
+	if err := adminSrv.Shutdown(httpCtx); err != nil {
+		log.Printf("admin server shutdown error: %v", err)
+	}
+}
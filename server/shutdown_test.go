@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestGracefulShutdownWaitsForDrain은 chunk0-6 회귀 테스트다: accept 루프가
+// main() 안에서 직접 돌던 시절엔 gracefulShutdown이 connWG를 기다리는 도중에
+// 프로세스 자체가 먼저 죽어서, 이 함수가 끝까지 실행될 기회조차 없었다. 이제
+// accept 루프는 자신만의 고루틴에서 돌므로, gracefulShutdown은 드레인이 끝날
+// 때까지 정상적으로 블로킹했다가 반환해야 한다.
+func TestGracefulShutdownWaitsForDrain(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	pool := &workerPool{
+		MaxWorkersCount: 1,
+		IdleTimeout:     time.Second,
+	}
+	pool.WorkerFunc = func(conn net.Conn) {}
+	pool.Start()
+
+	adminSrv := &http.Server{Addr: "127.0.0.1:0"}
+
+	var connWG sync.WaitGroup
+	connWG.Add(1) // 아직 안 끝난 연결 하나를 흉내낸다
+
+	done := make(chan struct{})
+	start := time.Now()
+	go func() {
+		gracefulShutdown(ln, pool, adminSrv, func() {}, &connWG)
+		close(done)
+	}()
+
+	// 드레인 대기 중임을 확인하기 위해 잠깐 기다린 뒤에야 연결을 끝낸다.
+	time.Sleep(100 * time.Millisecond)
+	select {
+	case <-done:
+		t.Fatal("gracefulShutdown returned before the in-flight connection finished")
+	default:
+	}
+	connWG.Done()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("gracefulShutdown did not return after connections drained")
+	}
+
+	if elapsed := time.Since(start); elapsed >= *shutdownTimeout {
+		t.Fatalf("gracefulShutdown took %v, expected the fast drain path well under -shutdown-timeout (%v)", elapsed, *shutdownTimeout)
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"expvar"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"syscall"
+)
+
+// [Best #19] TLS/mTLS 지원: crypto/tls 위에서 SNI에 따라 인증서를 고르고,
+// SIGHUP을 받으면 프로세스 재시작 없이 새 인증서를 반영한다 (GetCertificate + atomic.Value).
+var (
+	tlsCertFile  = flag.String("tls-cert", "", "TLS certificate file (PEM); enables TLS when set")
+	tlsKeyFile   = flag.String("tls-key", "", "TLS private key file (PEM)")
+	tlsClientCA  = flag.String("tls-client-ca", "", "PEM CA bundle to verify client certs (enables mTLS)")
+	tlsMinVerStr = flag.String("tls-min-version", "1.2", "Minimum TLS version: 1.0|1.1|1.2|1.3")
+
+	// fix: GetCertificate는 이전엔 hello를 그냥 버려서 실제로는 단일 인증서만
+	// 나갔다 (주석의 "SNI에 따라 인증서를 고르고"는 사실이 아니었다). 호스트별
+	// 인증서를 추가로 등록할 수 있게 "host=certfile:keyfile" 쌍을 콤마로
+	// 나열하는 플래그를 둔다.
+	tlsSNICerts = flag.String("tls-sni-certs", "", "Additional per-hostname certs as host=certfile:keyfile pairs, comma-separated (e.g. a.example.com=a.pem:a-key.pem,b.example.com=b.pem:b-key.pem)")
+)
+
+var (
+	tlsHandshakesTotal      = expvar.NewInt("tls_handshakes_total")
+	tlsHandshakeErrorsTotal = expvar.NewInt("tls_handshake_errors_total")
+)
+
+// keypair는 hot-reload되는 인증서/키 쌍을 담는다. atomic.Value에 저장해서
+// GetCertificate에서 락 없이 읽을 수 있도록 한다.
+var currentKeypair atomic.Value // holds *tls.Certificate
+
+// currentSNICerts는 -tls-sni-certs로 등록된 호스트별 인증서 맵이다. 키는 소문자
+// 호스트명. currentKeypair와 마찬가지로 SIGHUP 핫 리로드 대상이다.
+var currentSNICerts atomic.Value // holds map[string]*tls.Certificate
+
+func loadKeypair() (*tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(*tlsCertFile, *tlsKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("tls: load keypair: %w", err)
+	}
+	return &cert, nil
+}
+
+// loadSNICerts는 -tls-sni-certs의 "host=certfile:keyfile" 쌍들을 파싱해서
+// 호스트별 인증서 맵으로 읽어들인다. spec이 비어 있으면 빈 맵을 돌려준다.
+func loadSNICerts(spec string) (map[string]*tls.Certificate, error) {
+	certs := make(map[string]*tls.Certificate)
+	if spec == "" {
+		return certs, nil
+	}
+	for _, entry := range strings.Split(spec, ",") {
+		host, files, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("tls: malformed -tls-sni-certs entry %q (want host=certfile:keyfile)", entry)
+		}
+		certFile, keyFile, ok := strings.Cut(files, ":")
+		if !ok {
+			return nil, fmt.Errorf("tls: malformed -tls-sni-certs entry %q (want host=certfile:keyfile)", entry)
+		}
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls: load sni cert for %q: %w", host, err)
+		}
+		certs[strings.ToLower(host)] = &cert
+	}
+	return certs, nil
+}
+
+func tlsMinVersion(s string) (uint16, error) {
+	switch s {
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2", "":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("tls: unknown -tls-min-version %q", s)
+	}
+}
+
+// buildTLSConfig는 -tls-* 플래그로부터 tls.Config를 만든다. tlsCertFile이
+// 비어 있으면 (nil, nil)을 돌려줘서 호출자가 평문 리스너를 쓰게 한다.
+func buildTLSConfig() (*tls.Config, error) {
+	if *tlsCertFile == "" {
+		return nil, nil
+	}
+
+	cert, err := loadKeypair()
+	if err != nil {
+		return nil, err
+	}
+	currentKeypair.Store(cert)
+
+	sniCerts, err := loadSNICerts(*tlsSNICerts)
+	if err != nil {
+		return nil, err
+	}
+	currentSNICerts.Store(sniCerts)
+
+	minVersion, err := tlsMinVersion(*tlsMinVerStr)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{
+		MinVersion: minVersion,
+		// fix: hello.ServerName(SNI)으로 -tls-sni-certs에 등록된 호스트별
+		// 인증서를 먼저 찾고, 없으면(또는 클라이언트가 SNI를 안 보내면) 기본
+		// -tls-cert/-tls-key 인증서로 fall back한다.
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			if hello.ServerName != "" {
+				if certs, ok := currentSNICerts.Load().(map[string]*tls.Certificate); ok {
+					if cert, found := certs[strings.ToLower(hello.ServerName)]; found {
+						return cert, nil
+					}
+				}
+			}
+			return currentKeypair.Load().(*tls.Certificate), nil
+		},
+	}
+
+	if *tlsClientCA != "" {
+		caPEM, err := os.ReadFile(*tlsClientCA)
+		if err != nil {
+			return nil, fmt.Errorf("tls: read client CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("tls: no certs parsed from %s", *tlsClientCA)
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	// [Best #19] SIGHUP 핫 리로드: 무중단으로 만료 임박 인증서를 교체할 수 있게 한다.
+	go watchCertReload()
+
+	return cfg, nil
+}
+
+func watchCertReload() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for range hup {
+		cert, err := loadKeypair()
+		if err != nil {
+			log.Printf("tls: reload failed, keeping old cert: %v", err)
+			continue
+		}
+		sniCerts, err := loadSNICerts(*tlsSNICerts)
+		if err != nil {
+			log.Printf("tls: sni cert reload failed, keeping old certs: %v", err)
+			continue
+		}
+		currentKeypair.Store(cert)
+		currentSNICerts.Store(sniCerts)
+		log.Printf("🔐 tls: certificate reloaded from %s (sni hosts: %d)", *tlsCertFile, len(sniCerts))
+	}
+}
+
+// peerCertSubject는 TLS 연결의 피어 인증서 Subject(CN)를 로그용 문자열로 뽑아낸다.
+// mTLS가 꺼져 있거나 핸드셰이크가 아직 안 됐으면 빈 문자열을 돌려준다.
+func peerCertSubject(conn net.Conn) string {
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return ""
+	}
+	state := tlsConn.ConnectionState()
+	if len(state.PeerCertificates) == 0 {
+		return ""
+	}
+	return state.PeerCertificates[0].Subject.CommonName
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestKeypair generates a throwaway self-signed cert/key PEM pair for
+// exercising loadSNICerts without depending on fixtures on disk.
+func writeTestKeypair(t *testing.T, dir, name string) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Unix(0, 0),
+		NotAfter:     time.Unix(0, 0).Add(24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+
+	certFile = filepath.Join(dir, name+"-cert.pem")
+	keyFile = filepath.Join(dir, name+"-key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("write cert: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	return certFile, keyFile
+}
+
+// TestLoadSNICerts는 chunk0-3 회귀 테스트다: -tls-sni-certs 엔트리가 호스트별로
+// 올바르게 파싱되어 대소문자 구분 없이 조회 가능해야 한다.
+func TestLoadSNICerts(t *testing.T) {
+	dir := t.TempDir()
+	aCert, aKey := writeTestKeypair(t, dir, "a")
+	bCert, bKey := writeTestKeypair(t, dir, "b")
+
+	spec := "A.Example.com=" + aCert + ":" + aKey + ",b.example.com=" + bCert + ":" + bKey
+	certs, err := loadSNICerts(spec)
+	if err != nil {
+		t.Fatalf("loadSNICerts: %v", err)
+	}
+	if len(certs) != 2 {
+		t.Fatalf("expected 2 certs, got %d", len(certs))
+	}
+	if _, ok := certs["a.example.com"]; !ok {
+		t.Fatalf("expected lowercased host key %q present", "a.example.com")
+	}
+	if _, ok := certs["b.example.com"]; !ok {
+		t.Fatalf("expected host key %q present", "b.example.com")
+	}
+}
+
+func TestLoadSNICertsEmpty(t *testing.T) {
+	certs, err := loadSNICerts("")
+	if err != nil {
+		t.Fatalf("loadSNICerts(\"\"): %v", err)
+	}
+	if len(certs) != 0 {
+		t.Fatalf("expected empty map, got %d entries", len(certs))
+	}
+}
+
+func TestLoadSNICertsMalformed(t *testing.T) {
+	if _, err := loadSNICerts("not-a-valid-entry"); err == nil {
+		t.Fatal("expected error for malformed entry, got nil")
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+// [Best #24] OpenTelemetry 연동: 이 저장소엔 otel SDK 의존성이 없으므로(표준
+// 라이브러리만 쓰는 관례), 스팬 생성/전파/내보내기를 최소한으로 직접 구현한다.
+// OTEL_EXPORTER_OTLP_ENDPOINT가 설정되어 있으면 스팬을 JSON으로 그 엔드포인트에
+// best-effort로 전송한다 (실패해도 서비스 동작에 영향 없음).
+
+type span struct {
+	traceID string
+	spanID  string
+	name    string
+	start   time.Time
+}
+
+func newID(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand 실패는 사실상 발생하지 않지만, 트레이싱 때문에 연결을
+		// 끊을 이유는 없으므로 타임스탬프 기반 폴백을 쓴다.
+		return hex.EncodeToString([]byte(time.Now().String()))[:n*2]
+	}
+	return hex.EncodeToString(b)
+}
+
+// startSpan은 연결/요청 하나를 나타내는 스팬을 시작한다. parentTraceID가 nil이 아니면
+// (length-prefixed 코덱으로 전파받은 경우) 새 trace ID 대신 그대로 이어받는다.
+func startSpan(name string, parentTraceID *string) *span {
+	traceID := newID(16)
+	if parentTraceID != nil && *parentTraceID != "" {
+		traceID = *parentTraceID
+	}
+	return &span{
+		traceID: traceID,
+		spanID:  newID(8),
+		name:    name,
+		start:   time.Now(),
+	}
+}
+
+func (s *span) end(attrs map[string]string) {
+	duration := time.Since(s.start)
+	log.Printf("trace=%s span=%s name=%s duration=%s attrs=%v", s.traceID, s.spanID, s.name, duration, attrs)
+	exportSpan(s, duration, attrs)
+}
+
+// otlpEndpoint는 매 스팬마다 os.Getenv를 호출하지 않도록 프로세스 시작 시 한 번 읽는다.
+var otlpEndpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+
+// exportSpan은 OTEL_EXPORTER_OTLP_ENDPOINT가 설정된 경우에만, 실제 OTLP
+// protobuf가 아닌 단순화된 JSON 문서를 비동기로 전송한다. 실패는 로그만 남기고 무시한다.
+func exportSpan(s *span, duration time.Duration, attrs map[string]string) {
+	if otlpEndpoint == "" {
+		return
+	}
+	go func() {
+		body, err := json.Marshal(map[string]interface{}{
+			"traceId":           s.traceID,
+			"spanId":            s.spanID,
+			"name":              s.name,
+			"startTimeUnixNano": s.start.UnixNano(),
+			"durationNanos":     duration.Nanoseconds(),
+			"attributes":        attrs,
+		})
+		if err != nil {
+			return
+		}
+		resp, err := http.Post(otlpEndpoint+"/v1/traces", "application/json", bytes.NewReader(body))
+		if err != nil {
+			log.Printf("otel: export to %s failed: %v", otlpEndpoint, err)
+			return
+		}
+		resp.Body.Close()
+	}()
+}
+
+// traceIDHeaderLen은 length-prefixed 코덱 프레임 앞에 묻히는 raw trace ID 바이트 수.
+const traceIDHeaderLen = 16
+
+// traceIDMagic은 trace ID 헤더 앞에 붙는 4바이트 시그니처다. fix: 예전엔 코덱이
+// length-prefixed이기만 하면 프레임 맨 앞 16바이트를 무조건 trace ID로 간주하고
+// 떼어냈는데, 이 프로토콜 변경을 모르는 클라이언트(client 패키지는 실제로 이
+// 헤더를 보내지 않는다)가 보낸 일반 payload의 앞부분이 잘려나가 응답이 깨졌다.
+// 이제는 이 매직이 실제로 앞에 붙어 있을 때만 trace ID로 인식하고, 없으면
+// payload를 그대로 둔다.
+var traceIDMagic = [4]byte{0x54, 0x52, 0x43, 0x31} // "TRC1"
+
+// extractTraceID는 length-prefixed 코덱에서 프레임이 traceIDMagic으로 시작할 때만
+// 그 뒤의 trace ID를 읽어낸다. 매직이 없으면(= 트레이싱을 모르는 클라이언트)
+// 전파 없이 nil과 함께 원본 프레임을 그대로 돌려준다.
+func extractTraceID(frame []byte) (*string, []byte) {
+	if _, ok := codec.(LengthPrefixedCodec); !ok {
+		return nil, frame
+	}
+	if len(frame) < len(traceIDMagic)+traceIDHeaderLen {
+		return nil, frame
+	}
+	if !bytes.Equal(frame[:len(traceIDMagic)], traceIDMagic[:]) {
+		return nil, frame
+	}
+	rest := frame[len(traceIDMagic):]
+	id := hex.EncodeToString(rest[:traceIDHeaderLen])
+	return &id, rest[traceIDHeaderLen:]
+}
+
+// injectTraceID는 요청에서 traceIDMagic이 붙은 trace ID를 실제로 받았을 때만
+// (traceID != nil) 응답 프레임 앞에 매직+trace ID를 되돌려 붙인다. 트레이싱을
+// 모르는 클라이언트의 요청에는 아무것도 덧붙이지 않아 기존 payload가 그대로 나간다.
+func injectTraceID(traceID *string, payload []byte) []byte {
+	if traceID == nil {
+		return payload
+	}
+	if _, ok := codec.(LengthPrefixedCodec); !ok {
+		return payload
+	}
+	raw, err := hex.DecodeString(*traceID)
+	if err != nil || len(raw) != traceIDHeaderLen {
+		return payload
+	}
+	out := make([]byte, 0, len(traceIDMagic)+len(raw)+len(payload))
+	out = append(out, traceIDMagic[:]...)
+	out = append(out, raw...)
+	out = append(out, payload...)
+	return out
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// TestExtractTraceIDLeavesPlainPayloadUntouched은 chunk0-7 회귀 테스트다:
+// 트레이싱 매직 없이 length-prefixed로 온 일반 payload는 앞 16바이트가 잘려
+// 나가면 안 된다.
+func TestExtractTraceIDLeavesPlainPayloadUntouched(t *testing.T) {
+	prev := codec
+	codec = LengthPrefixedCodec{}
+	defer func() { codec = prev }()
+
+	want := []byte("HELLO WORLD THIS IS A TEST MESSAGE")
+	traceID, frame := extractTraceID(want)
+
+	if traceID != nil {
+		t.Fatalf("expected no trace ID without the magic prefix, got %q", *traceID)
+	}
+	if string(frame) != string(want) {
+		t.Fatalf("payload corrupted: got %q, want %q", frame, want)
+	}
+}
+
+// TestTraceIDRoundTrip는 매직이 실제로 붙어 있을 때만 추출/재부착되는지 확인한다.
+func TestTraceIDRoundTrip(t *testing.T) {
+	prev := codec
+	codec = LengthPrefixedCodec{}
+	defer func() { codec = prev }()
+
+	rawID := "0123456789abcdef0123456789abcdef"
+	raw, err := hex.DecodeString(rawID)
+	if err != nil || len(raw) != traceIDHeaderLen {
+		t.Fatalf("bad test trace id: %v", err)
+	}
+	payload := []byte("ECHO: hi")
+	frame := append(append(append([]byte{}, traceIDMagic[:]...), raw...), payload...)
+
+	traceID, rest := extractTraceID(frame)
+	if traceID == nil || *traceID != rawID {
+		t.Fatalf("expected trace id %q, got %v", rawID, traceID)
+	}
+	if string(rest) != string(payload) {
+		t.Fatalf("expected payload %q, got %q", payload, rest)
+	}
+
+	reply := injectTraceID(traceID, []byte("PONG"))
+	gotID, gotPayload := extractTraceID(reply)
+	if gotID == nil || *gotID != rawID {
+		t.Fatalf("round-tripped trace id mismatch: got %v", gotID)
+	}
+	if string(gotPayload) != "PONG" {
+		t.Fatalf("round-tripped payload mismatch: got %q", gotPayload)
+	}
+}
+
+// TestInjectTraceIDSkipsUntaggedRequests는 요청에 trace 헤더가 없었으면
+// (traceID == nil) 응답에도 아무것도 덧붙이지 않는지 확인한다.
+func TestInjectTraceIDSkipsUntaggedRequests(t *testing.T) {
+	prev := codec
+	codec = LengthPrefixedCodec{}
+	defer func() { codec = prev }()
+
+	payload := []byte("ECHO: plain client")
+	got := injectTraceID(nil, payload)
+	if string(got) != string(payload) {
+		t.Fatalf("expected untagged payload unchanged, got %q", got)
+	}
+}
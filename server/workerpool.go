@@ -0,0 +1,203 @@
+package main
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// [Best #17] 고루틴 재사용 (Reactor 패턴): fasthttp의 workerChan 설계를 참고하여
+// accept마다 고루틴을 새로 만드는 대신, 유휴 워커를 LRU 스택에 쌓아두고 재사용한다.
+// 이렇게 하면 수천 개의 짧은 연결이 몰려도 고루틴 churn과 GC 압박이 줄어든다.
+type workerChan struct {
+	lastUseTime time.Time
+	ch          chan net.Conn
+}
+
+// workerChanCap: 0이면 워커 고루틴과 accept 고루틴이 서로를 블로킹 없이 핸드오프할 수
+// 없으므로(runtime.GOMAXPROCS > 1인 환경에서 컨텍스트 스위치 비용 발생), fasthttp와
+// 동일하게 1짜리 버퍼를 둬서 핸드오프 지연을 최소화한다.
+const workerChanCap = 1
+
+type workerPool struct {
+	// WorkerFunc는 각 연결을 처리하는 실제 핸들러 (handleConnection을 감싼 클로저)
+	WorkerFunc func(conn net.Conn)
+
+	// MaxWorkersCount: 동시에 떠 있을 수 있는 최대 워커 고루틴 수 (-workers)
+	MaxWorkersCount int
+
+	// IdleTimeout: 이 시간 동안 새 연결을 받지 못한 워커는 종료되어 GC 대상이 된다
+	IdleTimeout time.Duration
+
+	lock         sync.Mutex
+	workersCount int
+	mustStop     bool
+
+	ready []*workerChan
+
+	stopCh chan struct{}
+
+	workerChanPool sync.Pool
+}
+
+func (wp *workerPool) Start() {
+	if wp.stopCh != nil {
+		panic("BUG: workerPool already started")
+	}
+	wp.stopCh = make(chan struct{})
+	stopCh := wp.stopCh
+	wp.workerChanPool.New = func() interface{} {
+		return &workerChan{
+			ch: make(chan net.Conn, workerChanCap),
+		}
+	}
+
+	go func() {
+		var scratch []*workerChan
+		for {
+			wp.clean(&scratch)
+			select {
+			case <-stopCh:
+				return
+			default:
+				time.Sleep(wp.IdleTimeout)
+			}
+		}
+	}()
+}
+
+func (wp *workerPool) Stop() {
+	if wp.stopCh == nil {
+		panic("BUG: workerPool wasn't started")
+	}
+	close(wp.stopCh)
+	wp.stopCh = nil
+
+	wp.lock.Lock()
+	ready := wp.ready
+	for i, w := range ready {
+		w.ch <- nil
+		ready[i] = nil
+	}
+	wp.ready = ready[:0]
+	wp.mustStop = true
+	wp.lock.Unlock()
+}
+
+// clean은 IdleTimeout보다 오래 쉰 워커들을 스택에서 제거하고 종료 신호를 보낸다.
+func (wp *workerPool) clean(scratch *[]*workerChan) {
+	criticalTime := time.Now().Add(-wp.IdleTimeout)
+
+	wp.lock.Lock()
+	ready := wp.ready
+	n := len(ready)
+
+	l, r, mid := 0, n-1, 0
+	for l <= r {
+		mid = (l + r) / 2
+		if criticalTime.After(ready[mid].lastUseTime) {
+			l = mid + 1
+		} else {
+			r = mid - 1
+		}
+	}
+	i := r
+	if i == -1 {
+		wp.lock.Unlock()
+		return
+	}
+
+	*scratch = append((*scratch)[:0], ready[:i+1]...)
+	m := copy(ready, ready[i+1:])
+	for i = m; i < n; i++ {
+		ready[i] = nil
+	}
+	wp.ready = ready[:m]
+	wp.lock.Unlock()
+
+	tmp := *scratch
+	for i := range tmp {
+		tmp[i].ch <- nil
+		tmp[i] = nil
+	}
+}
+
+// Serve는 conn을 유휴 워커에게 넘기거나, 여유가 있으면 새 워커를 기동한다.
+// 워커가 가득 차 있으면 false를 반환하므로 호출자가 conn.Close()로 거절할 수 있다.
+func (wp *workerPool) Serve(conn net.Conn) bool {
+	ch := wp.getCh()
+	if ch == nil {
+		return false
+	}
+	ch.ch <- conn
+	return true
+}
+
+func (wp *workerPool) getCh() *workerChan {
+	var ch *workerChan
+	createWorker := false
+
+	wp.lock.Lock()
+	ready := wp.ready
+	n := len(ready) - 1
+	if n < 0 {
+		if wp.workersCount < wp.MaxWorkersCount {
+			createWorker = true
+			wp.workersCount++
+		}
+	} else {
+		ch = ready[n]
+		ready[n] = nil
+		wp.ready = ready[:n]
+		workersIdle.Add(-1)
+	}
+	wp.lock.Unlock()
+
+	if ch == nil {
+		if !createWorker {
+			return nil
+		}
+		vch := wp.workerChanPool.Get()
+		ch = vch.(*workerChan)
+		go func() {
+			wp.workerFunc(ch)
+			wp.workerChanPool.Put(vch)
+		}()
+	}
+	return ch
+}
+
+func (wp *workerPool) release(ch *workerChan) bool {
+	ch.lastUseTime = time.Now()
+	wp.lock.Lock()
+	if wp.mustStop {
+		wp.lock.Unlock()
+		return false
+	}
+	wp.ready = append(wp.ready, ch)
+	workersIdle.Add(1)
+	wp.lock.Unlock()
+	return true
+}
+
+// workerFunc는 워커 고루틴의 본체. ch.ch에서 연결을 받아 처리하고, 다시 유휴 스택에
+// 돌아가 다음 연결을 기다린다. nil을 받거나 released에 실패하면 고루틴이 종료된다.
+func (wp *workerPool) workerFunc(ch *workerChan) {
+	for conn := range ch.ch {
+		if conn == nil {
+			break
+		}
+
+		workersActive.Add(1)
+		wp.WorkerFunc(conn)
+		workersActive.Add(-1)
+
+		if !wp.release(ch) {
+			break
+		}
+	}
+
+	wp.lock.Lock()
+	wp.workersCount--
+	wp.lock.Unlock()
+}